@@ -0,0 +1,92 @@
+package hive_rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/hive/simulators/ethereum/engine/globals"
+)
+
+// Default batching thresholds: a batch size in elements and a latency
+// window. BatchCall and BatchCallContext do not consult them: they send all
+// of the elements they are given as one request, whatever their number, and
+// do not split, hold back or time-flush a batch.
+const (
+	DefaultBatchSize    = 100
+	DefaultBatchLatency = 20 * time.Millisecond
+)
+
+// BatchCallContext gathers the given rpc.BatchElems and flushes them as a
+// single eth-namespace JSON-RPC batch request, filling in each element's
+// Result/Error in place.
+func (ec *HiveRPCEngineClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return ec.cEth.BatchCallContext(ctx, b)
+}
+
+// BatchCall is the non-context convenience wrapper around BatchCallContext,
+// matching the naming of the single-call CallContext/Call pair already on
+// this client.
+func (ec *HiveRPCEngineClient) BatchCall(b []rpc.BatchElem) error {
+	return ec.BatchCallContext(context.Background(), b)
+}
+
+// GetNextAccountNoncesBatch is the batched counterpart of
+// GetNextAccountNonce: it resolves the next nonce for every account in a
+// single round trip instead of one `eth_getTransactionCount` call per
+// account. Accounts already tracked in accTxInfoMap and up to date with the
+// current head are resolved locally, without consuming a batch slot.
+func (ec *HiveRPCEngineClient) GetNextAccountNoncesBatch(testCtx context.Context, accounts []common.Address) (map[common.Address]uint64, error) {
+	ctx, cancel := context.WithTimeout(testCtx, globals.RPCTimeout)
+	defer cancel()
+	head, err := ec.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[common.Address]uint64, len(accounts))
+	var toFetch []common.Address
+	for _, account := range accounts {
+		if accTxInfo, ok := ec.accTxInfoMap[account]; ok && accTxInfo != nil &&
+			(accTxInfo.PreviousBlock == head.Hash() || accTxInfo.PreviousBlock == head.ParentHash) {
+			accTxInfo.PreviousBlock = head.Hash()
+			accTxInfo.PreviousNonce++
+			result[account] = accTxInfo.PreviousNonce
+			continue
+		}
+		toFetch = append(toFetch, account)
+	}
+	if len(toFetch) == 0 {
+		return result, nil
+	}
+
+	batch := make([]rpc.BatchElem, len(toFetch))
+	nonces := make([]hexutil.Uint64, len(toFetch))
+	blockArg := toBlockNumArg(head.Number)
+	for i, account := range toFetch {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getTransactionCount",
+			Args:   []interface{}{account, blockArg},
+			Result: &nonces[i],
+		}
+	}
+	ctx, cancel = context.WithTimeout(testCtx, globals.RPCTimeout)
+	defer cancel()
+	if err := ec.BatchCallContext(ctx, batch); err != nil {
+		return nil, err
+	}
+	for i, account := range toFetch {
+		if batch[i].Error != nil {
+			return nil, batch[i].Error
+		}
+		nonce := uint64(nonces[i])
+		ec.accTxInfoMap[account] = &AccountTransactionInfo{
+			PreviousBlock: head.Hash(),
+			PreviousNonce: nonce,
+		}
+		result[account] = nonce
+	}
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package hive_rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// perRequestLatency models the per-round-trip cost a real client pays
+// talking to a node over the network, so the benchmark reflects why
+// batching helps rather than just measuring loopback overhead.
+const perRequestLatency = 2 * time.Millisecond
+
+type jsonrpcMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+type jsonrpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// newNonceServer returns a JSON-RPC server that answers eth_getTransactionCount
+// with a fixed nonce, honoring both single-call and batch-call request
+// bodies, after sleeping latency once per HTTP round trip.
+func newNonceServer(latency time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(latency)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			var reqs []jsonrpcMessage
+			if err := json.Unmarshal(body, &reqs); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			resps := make([]jsonrpcResponse, len(reqs))
+			for i, req := range reqs {
+				resps[i] = jsonrpcResponse{Version: "2.0", ID: req.ID, Result: json.RawMessage(`"0x0"`)}
+			}
+			json.NewEncoder(w).Encode(resps)
+			return
+		}
+		var req jsonrpcMessage
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(jsonrpcResponse{Version: "2.0", ID: req.ID, Result: json.RawMessage(`"0x0"`)})
+	}))
+}
+
+func percentileOf(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(p * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+func reportPercentiles(b *testing.B, samples []time.Duration) {
+	b.ReportMetric(float64(percentileOf(samples, 0.50).Microseconds()), "p50-us")
+	b.ReportMetric(float64(percentileOf(samples, 0.99).Microseconds()), "p99-us")
+}
+
+// BenchmarkGetNoncesPerCall issues one eth_getTransactionCount per account,
+// the path every method on this client used before BatchCallContext.
+func BenchmarkGetNoncesPerCall(b *testing.B) {
+	const accounts = 50
+	srv := newNonceServer(perRequestLatency)
+	defer srv.Close()
+	rpcClient, err := rpc.DialHTTP(srv.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer rpcClient.Close()
+
+	addrs := make([]common.Address, accounts)
+	for i := range addrs {
+		addrs[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+
+	samples := make([]time.Duration, 0, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		for _, addr := range addrs {
+			var nonce hexutil.Uint64
+			if err := rpcClient.CallContext(context.Background(), &nonce, "eth_getTransactionCount", addr, "latest"); err != nil {
+				b.Fatal(err)
+			}
+		}
+		samples = append(samples, time.Since(start))
+	}
+	b.StopTimer()
+	reportPercentiles(b, samples)
+}
+
+// BenchmarkGetNoncesBatch issues a single batched eth_getTransactionCount
+// round trip for the same account set, via BatchCallContext.
+func BenchmarkGetNoncesBatch(b *testing.B) {
+	const accounts = 50
+	srv := newNonceServer(perRequestLatency)
+	defer srv.Close()
+	rpcClient, err := rpc.DialHTTP(srv.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer rpcClient.Close()
+	ec := &HiveRPCEngineClient{cEth: rpcClient}
+
+	addrs := make([]common.Address, accounts)
+	for i := range addrs {
+		addrs[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+	}
+
+	samples := make([]time.Duration, 0, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		batch := make([]rpc.BatchElem, accounts)
+		results := make([]hexutil.Uint64, accounts)
+		for j, addr := range addrs {
+			batch[j] = rpc.BatchElem{
+				Method: "eth_getTransactionCount",
+				Args:   []interface{}{addr, "latest"},
+				Result: &results[j],
+			}
+		}
+		if err := ec.BatchCallContext(context.Background(), batch); err != nil {
+			b.Fatal(err)
+		}
+		samples = append(samples, time.Since(start))
+	}
+	b.StopTimer()
+	reportPercentiles(b, samples)
+}
@@ -0,0 +1,188 @@
+package hive_rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// TracerConfig selects and configures a debug_trace* tracer. Tracer is one
+// of the built-in JS tracers (e.g. "callTracer", "prestateTracer"), or empty
+// for the default opcode-level (struct-log) tracer. Config is passed through
+// verbatim as the tracer's own config object.
+type TracerConfig struct {
+	Tracer string      `json:"tracer,omitempty"`
+	Config interface{} `json:"tracerConfig,omitempty"`
+}
+
+// CallFrame mirrors the shape returned by go-ethereum's callTracer: a single
+// call (or the top-level execution) plus its nested sub-calls.
+type CallFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   string         `json:"input"`
+	Output  string         `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Calls   []CallFrame    `json:"calls,omitempty"`
+}
+
+// PrestateAccount is a single account's pre-call state, as reported by
+// go-ethereum's prestateTracer.
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    string                      `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// PrestateResult maps every account touched by the traced call/transaction
+// to its state immediately before execution.
+type PrestateResult map[common.Address]PrestateAccount
+
+// StructLogResult mirrors go-ethereum's default (opcode-level) tracer
+// output, as returned when no tracer name is supplied.
+type StructLogResult struct {
+	Gas         hexutil.Uint64 `json:"gas"`
+	Failed      bool           `json:"failed"`
+	ReturnValue string         `json:"returnValue"`
+	StructLogs  []StructLog    `json:"structLogs"`
+}
+
+type StructLog struct {
+	Pc      uint64         `json:"pc"`
+	Op      string         `json:"op"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasCost hexutil.Uint64 `json:"gasCost"`
+	Depth   int            `json:"depth"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// TraceResult wraps the response of a single-transaction/call debug trace.
+// For a tracer this package models, exactly one of CallTracer,
+// PrestateTracer or StructLogTracer is populated, depending on which tracer
+// was requested; for any other tracer only Raw is. Raw always holds the
+// undecoded response so callers that need a tracer this package doesn't
+// model yet can still inspect it.
+type TraceResult struct {
+	CallTracer      *CallFrame
+	PrestateTracer  PrestateResult
+	StructLogTracer *StructLogResult
+	Raw             json.RawMessage
+}
+
+// BlockTraceResult is one transaction's entry in a debug_traceBlockBy*
+// response: either Trace is populated, or Error is, per go-ethereum's
+// per-tx error reporting for block traces.
+type BlockTraceResult struct {
+	TxHash common.Hash `json:"txHash"`
+	Trace  *TraceResult
+	Error  string `json:"error,omitempty"`
+}
+
+// rawTxTraceResult mirrors the on-the-wire shape of one debug_traceBlockBy*
+// array entry, before its Result is decoded against the requested tracer.
+type rawTxTraceResult struct {
+	TxHash common.Hash     `json:"txHash"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func defaultTracerConfig(tracer string, tracerConfig interface{}) *TracerConfig {
+	if tracer == "" {
+		tracer = "callTracer"
+	}
+	return &TracerConfig{Tracer: tracer, Config: tracerConfig}
+}
+
+// decodeTraceResult decodes a single tracer response (one call or
+// transaction) according to which tracer produced it.
+func decodeTraceResult(raw json.RawMessage, tracerName string) (*TraceResult, error) {
+	result := &TraceResult{Raw: raw}
+	switch tracerName {
+	case "callTracer":
+		var frame CallFrame
+		if err := json.Unmarshal(raw, &frame); err != nil {
+			return nil, fmt.Errorf("unable to decode callTracer response: %v", err)
+		}
+		result.CallTracer = &frame
+	case "prestateTracer":
+		var prestate PrestateResult
+		if err := json.Unmarshal(raw, &prestate); err != nil {
+			return nil, fmt.Errorf("unable to decode prestateTracer response: %v", err)
+		}
+		result.PrestateTracer = prestate
+	case "":
+		var sl StructLogResult
+		if err := json.Unmarshal(raw, &sl); err != nil {
+			return nil, fmt.Errorf("unable to decode struct-log trace response: %v", err)
+		}
+		result.StructLogTracer = &sl
+	}
+	return result, nil
+}
+
+// debugTrace issues a debug_trace* call that traces a single call or
+// transaction, decoding the response against the requested tracer.
+func (ec *HiveRPCEngineClient) debugTrace(ctx context.Context, method string, tracer *TracerConfig, params ...interface{}) (*TraceResult, error) {
+	var raw json.RawMessage
+	args := append(append([]interface{}{}, params...), tracer)
+	if err := ec.callWithFallback(ctx, &raw, method, args...); err != nil {
+		return nil, err
+	}
+	return decodeTraceResult(raw, tracer.Tracer)
+}
+
+// debugTraceBlock issues a debug_traceBlockBy* call, which returns one
+// result per transaction in the block rather than a single trace.
+func (ec *HiveRPCEngineClient) debugTraceBlock(ctx context.Context, method string, tracer *TracerConfig, params ...interface{}) ([]BlockTraceResult, error) {
+	var raw []rawTxTraceResult
+	args := append(append([]interface{}{}, params...), tracer)
+	if err := ec.callWithFallback(ctx, &raw, method, args...); err != nil {
+		return nil, err
+	}
+	results := make([]BlockTraceResult, len(raw))
+	for i, entry := range raw {
+		results[i] = BlockTraceResult{TxHash: entry.TxHash, Error: entry.Error}
+		if entry.Error != "" || len(entry.Result) == 0 {
+			continue
+		}
+		trace, err := decodeTraceResult(entry.Result, tracer.Tracer)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s: %v", entry.TxHash, err)
+		}
+		results[i].Trace = trace
+	}
+	return results, nil
+}
+
+// DebugTraceBlockByHash traces every transaction in the block identified by
+// hash, using the given tracer (defaults to "callTracer" if tracer is empty).
+func (ec *HiveRPCEngineClient) DebugTraceBlockByHash(ctx context.Context, hash common.Hash, tracer string, tracerConfig interface{}) ([]BlockTraceResult, error) {
+	return ec.debugTraceBlock(ctx, "debug_traceBlockByHash", defaultTracerConfig(tracer, tracerConfig), hash)
+}
+
+// DebugTraceBlockByNumber traces every transaction in the block at number,
+// using the given tracer (defaults to "callTracer" if tracer is empty). A
+// nil number means "latest", mirroring HeaderByNumber.
+func (ec *HiveRPCEngineClient) DebugTraceBlockByNumber(ctx context.Context, number *big.Int, tracer string, tracerConfig interface{}) ([]BlockTraceResult, error) {
+	return ec.debugTraceBlock(ctx, "debug_traceBlockByNumber", defaultTracerConfig(tracer, tracerConfig), toBlockNumArg(number))
+}
+
+// DebugTraceTransaction traces a single already-mined transaction.
+func (ec *HiveRPCEngineClient) DebugTraceTransaction(ctx context.Context, txHash common.Hash, tracer string, tracerConfig interface{}) (*TraceResult, error) {
+	return ec.debugTrace(ctx, "debug_traceTransaction", defaultTracerConfig(tracer, tracerConfig), txHash)
+}
+
+// DebugTraceCall traces a call that is never mined, executed against the
+// state at blockNumOrHash (e.g. "latest", or a specific block number).
+func (ec *HiveRPCEngineClient) DebugTraceCall(ctx context.Context, callArgs interface{}, blockNumOrHash string, tracer string, tracerConfig interface{}) (*TraceResult, error) {
+	return ec.debugTrace(ctx, "debug_traceCall", defaultTracerConfig(tracer, tracerConfig), callArgs, blockNumOrHash)
+}
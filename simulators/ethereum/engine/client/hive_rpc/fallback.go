@@ -0,0 +1,134 @@
+package hive_rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// defaultFallbackAllowlist are the eth- and debug-namespace methods that get retried
+// against FallbackRPCURL when the client under test can't answer them.
+// engine_* methods are never eligible, regardless of this list, so
+// consensus-critical calls always hit the client under test.
+var defaultFallbackAllowlist = map[string]bool{
+	"eth_getBlockByHash":        true,
+	"eth_getBlockByNumber":      true,
+	"eth_getTransactionByHash":  true,
+	"eth_getTransactionReceipt": true,
+	"eth_getProof":              true,
+	"eth_getBalance":            true,
+	"eth_getCode":               true,
+	"eth_getStorageAt":          true,
+	"debug_traceTransaction":    true,
+	"debug_traceBlockByHash":    true,
+	"debug_traceBlockByNumber":  true,
+	"debug_traceCall":           true,
+}
+
+// isFallbackEligible reports whether method may be retried against the
+// fallback RPC provider. engine_* methods are hard-excluded.
+func isFallbackEligible(method string, allowlist map[string]bool) bool {
+	if strings.HasPrefix(method, "engine_") {
+		return false
+	}
+	if allowlist == nil {
+		return defaultFallbackAllowlist[method]
+	}
+	return allowlist[method]
+}
+
+// missingStateErrorSubstrings matches the generic (non-"not found") JSON-RPC
+// errors a non-archive node returns when asked for state it has pruned,
+// e.g. "missing trie node" from eth_getProof/debug_trace* over old blocks.
+// These are worth retrying against an archive fallback even though they
+// aren't reported as ethereum.NotFound or a "method not found" style error.
+var missingStateErrorSubstrings = []string{
+	"missing trie node",
+	"state not available",
+	"pruned",
+}
+
+// isFallbackableError reports whether err looks like a "this node doesn't
+// have the answer" response rather than a genuine execution failure, i.e.
+// one worth retrying against an archive/fallback provider.
+func isFallbackableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// -32601 is the standard JSON-RPC "method not found" code; match it
+		// directly rather than sniffing the message, and only fall back to
+		// an anchored "method not found" substring for servers that report
+		// it as a generic error without the code set. A bare "not found"
+		// substring is too broad: it also matches genuine execution
+		// failures (e.g. "execution reverted: account not found"), which
+		// must surface as real client-under-test failures, not be silently
+		// answered by the fallback provider.
+		if rpcErr.ErrorCode() == -32601 {
+			return true
+		}
+		msg := strings.ToLower(rpcErr.Error())
+		if strings.Contains(msg, "method not found") {
+			return true
+		}
+		for _, sub := range missingStateErrorSubstrings {
+			if strings.Contains(msg, sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callWithFallback performs the call against ec.cEth, and if it fails with
+// a not-found-style error and method is fallback-eligible, retries the same
+// call against ec.cFallback.
+func (ec *HiveRPCEngineClient) callWithFallback(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return ec.callWithFallbackValidate(ctx, result, nil, method, args...)
+}
+
+// callWithFallbackValidate is callWithFallback with an extra validate hook,
+// run after every successful call. Some eth-namespace methods report "not
+// found" as a nil/zero result with no error (e.g. eth_getBlockByNumber for
+// an unknown number), rather than as an error isFallbackableError can see;
+// validate lets the caller turn that into an error so it still triggers the
+// fallback retry instead of being returned as a false "found" zero value.
+func (ec *HiveRPCEngineClient) callWithFallbackValidate(ctx context.Context, result interface{}, validate func() error, method string, args ...interface{}) error {
+	err := ec.cEth.CallContext(ctx, result, method, args...)
+	if err == nil && validate != nil {
+		err = validate()
+	}
+	if err == nil || ec.cFallback == nil || !isFallbackEligible(method, ec.fallbackAllowlist) || !isFallbackableError(err) {
+		return err
+	}
+	err = ec.cFallback.CallContext(ctx, result, method, args...)
+	if err == nil && validate != nil {
+		err = validate()
+	}
+	return err
+}
+
+// setFallbackRPC dials FallbackRPCURL, if set, so that eligible eth-namespace
+// calls can transparently retry against it. A dial failure is returned to
+// the caller, and StartClient fails with it. With no FallbackRPCURL set,
+// nothing is dialed and the client works against the client under test
+// alone.
+func (ec *HiveRPCEngineClient) setFallbackRPC(ctx context.Context, fallbackURL string, allowlist map[string]bool) error {
+	if fallbackURL == "" {
+		return nil
+	}
+	c, err := rpc.DialContext(ctx, fallbackURL)
+	if err != nil {
+		return err
+	}
+	ec.cFallback = c
+	ec.fallbackAllowlist = allowlist
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -31,16 +32,26 @@ type HiveRPCEngineStarter struct {
 	TerminalTotalDifficulty *big.Int
 	EnginePort              int
 	EthPort                 int
+	EnginePortWS            int
+	EthPortWS               int
 	JWTSecret               []byte
+
+	// FallbackRPCURL, if set, is consulted for eligible eth-namespace
+	// methods (e.g. eth_getProof) that the launched client can't answer,
+	// such as a local Erigon archive or a hosted provider like Infura.
+	FallbackRPCURL    string
+	FallbackAllowlist map[string]bool
 }
 
 func (s HiveRPCEngineStarter) StartClient(T *hivesim.T, testContext context.Context, ClientParams hivesim.Params, ClientFiles hivesim.Params, bootClients ...client.EngineClient) (client.EngineClient, error) {
 	var (
-		clientType = s.ClientType
-		enginePort = s.EnginePort
-		ethPort    = s.EthPort
-		jwtSecret  = s.JWTSecret
-		ttd        = s.TerminalTotalDifficulty
+		clientType   = s.ClientType
+		enginePort   = s.EnginePort
+		ethPort      = s.EthPort
+		enginePortWS = s.EnginePortWS
+		ethPortWS    = s.EthPortWS
+		jwtSecret    = s.JWTSecret
+		ttd          = s.TerminalTotalDifficulty
 	)
 	if clientType == "" {
 		cs, err := T.Sim.ClientTypes()
@@ -58,6 +69,12 @@ func (s HiveRPCEngineStarter) StartClient(T *hivesim.T, testContext context.Cont
 	if ethPort == 0 {
 		ethPort = globals.EthPortHTTP
 	}
+	if enginePortWS == 0 {
+		enginePortWS = globals.EnginePortWS
+	}
+	if ethPortWS == 0 {
+		ethPortWS = globals.EthPortWS
+	}
 	if jwtSecret == nil {
 		jwtSecret = globals.DefaultJwtTokenSecretBytes
 	}
@@ -104,6 +121,11 @@ func (s HiveRPCEngineStarter) StartClient(T *hivesim.T, testContext context.Cont
 		Hc:    c,
 		Inner: http.DefaultTransport,
 	})
+	ec.ethPortWS = ethPortWS
+	ec.enginePortWS = enginePortWS
+	if err := ec.setFallbackRPC(testContext, s.FallbackRPCURL, s.FallbackAllowlist); err != nil {
+		return nil, fmt.Errorf("unable to dial fallback RPC provider: %v", err)
+	}
 	return ec, nil
 }
 
@@ -131,9 +153,30 @@ func CheckEthEngineLive(c *hivesim.Client) error {
 			}
 		}
 	}
+	// The WS ports are probed best-effort only: many clients (e.g. geth)
+	// serve the eth/engine namespaces over HTTP+WS on the same port and
+	// never open a distinct WS listener, so treating these as mandatory
+	// would fail StartClient for every such client, not just the ones that
+	// actually subscribe to something. A client that genuinely has no WS
+	// listener will instead get a clear dial error the first time one of
+	// the Subscribe* methods is used.
+	probeWSPort(c, globals.EthPortWS)
+	probeWSPort(c, globals.EnginePortWS)
 	return nil
 }
 
+// probeWSPort best-effort checks whether a WS listener is open on port,
+// within its own short budget, separate from CheckEthEngineLive's mandatory
+// HTTP-port budget. The result is intentionally discarded.
+func probeWSPort(c *hivesim.Client, port int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var dialer net.Dialer
+	if conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", c.IP, port)); err == nil {
+		conn.Close()
+	}
+}
+
 type AccountTransactionInfo struct {
 	PreviousBlock common.Hash
 	PreviousNonce uint64
@@ -156,6 +199,32 @@ type HiveRPCEngineClient struct {
 	latestPayloadSent          *api.ExecutableDataV1
 	latestPayloadStatusReponse *api.PayloadStatusV1
 
+	// Latest V2/V3 engine info, tracked separately since their payloads and
+	// attributes carry withdrawals/blob fields the V1 structs don't have.
+	latestPAttrV2Sent      *api.PayloadAttributesV2
+	latestPAttrV3Sent      *api.PayloadAttributesV3
+	latestPayloadV2Sent    *api.ExecutableDataV2
+	latestPayloadV3Sent    *api.ExecutableDataV3
+	latestGetPayloadV2Resp *api.GetPayloadV2Response
+	latestGetPayloadV3Resp *api.GetPayloadV3Response
+
+	// WebSocket transport for Subscribe*, dialed lazily on first use.
+	ethPortWS    int
+	enginePortWS int
+	wsMu         sync.Mutex
+	cEthWS       *rpc.Client
+	cEngineWS    *rpc.Client
+
+	// Fallback RPC provider for eth-namespace methods the client under
+	// test can't answer (e.g. missing historical state). Never consulted
+	// for the engine namespace.
+	cFallback         *rpc.Client
+	fallbackAllowlist map[string]bool
+
+	// Recorder for engine_* calls, attached via SetCallRecorder. Nil by
+	// default, so most tests pay no recording overhead.
+	recorder *EngineCallRecorder
+
 	// Test account nonces
 	accTxInfoMap map[common.Address]*AccountTransactionInfo
 }
@@ -223,10 +292,12 @@ func toBlockNumArg(number *big.Int) string {
 
 func (ec *HiveRPCEngineClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
 	var header *types.Header
-	err := ec.cEth.CallContext(ctx, &header, "eth_getBlockByNumber", toBlockNumArg(number), false)
-	if err == nil && header == nil {
-		err = ethereum.NotFound
-	}
+	err := ec.callWithFallbackValidate(ctx, &header, func() error {
+		if header == nil {
+			return ethereum.NotFound
+		}
+		return nil
+	}, "eth_getBlockByNumber", toBlockNumArg(number), false)
 	return header, err
 }
 
@@ -251,7 +322,7 @@ func (tdh *TotalDifficultyHeader) UnmarshalJSON(data []byte) error {
 
 func (ec *HiveRPCEngineClient) GetTotalDifficulty(ctx context.Context) (*big.Int, error) {
 	var td *TotalDifficultyHeader
-	if err := ec.cEth.CallContext(ctx, &td, "eth_getBlockByNumber", "latest", false); err == nil {
+	if err := ec.callWithFallback(ctx, &td, "eth_getBlockByNumber", "latest", false); err == nil {
 		return td.TotalDifficulty.ToInt(), nil
 	} else {
 		return nil, err
@@ -261,6 +332,15 @@ func (ec *HiveRPCEngineClient) GetTotalDifficulty(ctx context.Context) (*big.Int
 func (ec *HiveRPCEngineClient) Close() error {
 	ec.c.Close()
 	ec.Client.Close()
+	if ec.cEthWS != nil {
+		ec.cEthWS.Close()
+	}
+	if ec.cEngineWS != nil {
+		ec.cEngineWS.Close()
+	}
+	if ec.cFallback != nil {
+		ec.cFallback.Close()
+	}
 	return nil
 }
 
@@ -293,39 +373,110 @@ func (ec *HiveRPCEngineClient) PrepareDefaultAuthCallToken() error {
 // Engine API Call Methods
 func (ec *HiveRPCEngineClient) ForkchoiceUpdatedV1(ctx context.Context, fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV1) (api.ForkChoiceResponse, error) {
 	var result api.ForkChoiceResponse
-	if err := ec.PrepareDefaultAuthCallToken(); err != nil {
-		return result, err
-	}
 	ec.latestFcUStateSent = fcState
 	ec.latestPAttrSent = pAttributes
-	err := ec.c.CallContext(ctx, &result, "engine_forkchoiceUpdatedV1", fcState, pAttributes)
+	err := ec.engineCallContext(ctx, &result, "engine_forkchoiceUpdatedV1", fcState, pAttributes)
 	ec.latestFcUResponse = &result
 	return result, err
 }
 
 func (ec *HiveRPCEngineClient) GetPayloadV1(ctx context.Context, payloadId *api.PayloadID) (api.ExecutableDataV1, error) {
 	var result api.ExecutableDataV1
-	if err := ec.PrepareDefaultAuthCallToken(); err != nil {
-		return result, err
-	}
-	err := ec.c.CallContext(ctx, &result, "engine_getPayloadV1", payloadId)
+	err := ec.engineCallContext(ctx, &result, "engine_getPayloadV1", payloadId)
 	return result, err
 }
 
 func (ec *HiveRPCEngineClient) NewPayloadV1(ctx context.Context, payload *api.ExecutableDataV1) (api.PayloadStatusV1, error) {
 	var result api.PayloadStatusV1
-	if err := ec.PrepareDefaultAuthCallToken(); err != nil {
-		return result, err
-	}
 	ec.latestPayloadSent = payload
-	err := ec.c.CallContext(ctx, &result, "engine_newPayloadV1", payload)
+	err := ec.engineCallContext(ctx, &result, "engine_newPayloadV1", payload)
 	ec.latestPayloadStatusReponse = &result
 	return result, err
 }
 
 func (ec *HiveRPCEngineClient) ExchangeTransitionConfigurationV1(ctx context.Context, tConf *api.TransitionConfigurationV1) (api.TransitionConfigurationV1, error) {
 	var result api.TransitionConfigurationV1
-	err := ec.c.CallContext(ctx, &result, "engine_exchangeTransitionConfigurationV1", tConf)
+	err := ec.engineCallContext(ctx, &result, "engine_exchangeTransitionConfigurationV1", tConf)
+	return result, err
+}
+
+// ForkchoiceUpdatedV2 is the Shanghai-capable update call: pAttributes may
+// carry withdrawals, which V1 clients must reject.
+func (ec *HiveRPCEngineClient) ForkchoiceUpdatedV2(ctx context.Context, fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV2) (api.ForkChoiceResponse, error) {
+	var result api.ForkChoiceResponse
+	ec.latestFcUStateSent = fcState
+	ec.latestPAttrV2Sent = pAttributes
+	err := ec.engineCallContext(ctx, &result, "engine_forkchoiceUpdatedV2", fcState, pAttributes)
+	ec.latestFcUResponse = &result
+	return result, err
+}
+
+// ForkchoiceUpdatedV3 is the Cancun-capable update call: pAttributes may
+// additionally carry the parent beacon block root.
+func (ec *HiveRPCEngineClient) ForkchoiceUpdatedV3(ctx context.Context, fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV3) (api.ForkChoiceResponse, error) {
+	var result api.ForkChoiceResponse
+	ec.latestFcUStateSent = fcState
+	ec.latestPAttrV3Sent = pAttributes
+	err := ec.engineCallContext(ctx, &result, "engine_forkchoiceUpdatedV3", fcState, pAttributes)
+	ec.latestFcUResponse = &result
+	return result, err
+}
+
+func (ec *HiveRPCEngineClient) GetPayloadV2(ctx context.Context, payloadId *api.PayloadID) (api.GetPayloadV2Response, error) {
+	var result api.GetPayloadV2Response
+	err := ec.engineCallContext(ctx, &result, "engine_getPayloadV2", payloadId)
+	ec.latestGetPayloadV2Resp = &result
+	return result, err
+}
+
+func (ec *HiveRPCEngineClient) GetPayloadV3(ctx context.Context, payloadId *api.PayloadID) (api.GetPayloadV3Response, error) {
+	var result api.GetPayloadV3Response
+	err := ec.engineCallContext(ctx, &result, "engine_getPayloadV3", payloadId)
+	ec.latestGetPayloadV3Resp = &result
+	return result, err
+}
+
+func (ec *HiveRPCEngineClient) NewPayloadV2(ctx context.Context, payload *api.ExecutableDataV2) (api.PayloadStatusV1, error) {
+	var result api.PayloadStatusV1
+	ec.latestPayloadV2Sent = payload
+	err := ec.engineCallContext(ctx, &result, "engine_newPayloadV2", payload)
+	ec.latestPayloadStatusReponse = &result
+	return result, err
+}
+
+// NewPayloadV3 additionally takes the versioned hashes of the blobs
+// referenced by the payload's transactions, plus the parent beacon block
+// root, as required by the Cancun engine API.
+func (ec *HiveRPCEngineClient) NewPayloadV3(ctx context.Context, payload *api.ExecutableDataV3, versionedHashes []common.Hash, parentBeaconBlockRoot *common.Hash) (api.PayloadStatusV1, error) {
+	var result api.PayloadStatusV1
+	ec.latestPayloadV3Sent = payload
+	err := ec.engineCallContext(ctx, &result, "engine_newPayloadV3", payload, versionedHashes, parentBeaconBlockRoot)
+	ec.latestPayloadStatusReponse = &result
+	return result, err
+}
+
+// GetPayloadBodiesByHashV1 returns the execution payload bodies for the
+// given block hashes, as introduced for Shanghai chain-sync use cases.
+func (ec *HiveRPCEngineClient) GetPayloadBodiesByHashV1(ctx context.Context, hashes []common.Hash) ([]*api.ExecutionPayloadBodyV1, error) {
+	var result []*api.ExecutionPayloadBodyV1
+	err := ec.engineCallContext(ctx, &result, "engine_getPayloadBodiesByHashV1", hashes)
+	return result, err
+}
+
+// GetPayloadBodiesByRangeV1 returns the execution payload bodies for `count`
+// blocks starting at `start`.
+func (ec *HiveRPCEngineClient) GetPayloadBodiesByRangeV1(ctx context.Context, start, count uint64) ([]*api.ExecutionPayloadBodyV1, error) {
+	var result []*api.ExecutionPayloadBodyV1
+	err := ec.engineCallContext(ctx, &result, "engine_getPayloadBodiesByRangeV1", hexutil.Uint64(start), hexutil.Uint64(count))
+	return result, err
+}
+
+// ExchangeCapabilities advertises the engine methods this client (the
+// consensus layer simulator) supports, and returns the methods the
+// execution client under test supports in turn.
+func (ec *HiveRPCEngineClient) ExchangeCapabilities(ctx context.Context, capabilities []string) ([]string, error) {
+	var result []string
+	err := ec.engineCallContext(ctx, &result, "engine_exchangeCapabilities", capabilities)
 	return result, err
 }
 
@@ -378,3 +529,27 @@ func (ec *HiveRPCEngineClient) LatestForkchoiceResponse() *api.ForkChoiceRespons
 func (ec *HiveRPCEngineClient) LatestNewPayloadResponse() *api.PayloadStatusV1 {
 	return ec.latestPayloadStatusReponse
 }
+
+func (ec *HiveRPCEngineClient) LatestForkchoiceSentV2() (fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV2) {
+	return ec.latestFcUStateSent, ec.latestPAttrV2Sent
+}
+
+func (ec *HiveRPCEngineClient) LatestForkchoiceSentV3() (fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV3) {
+	return ec.latestFcUStateSent, ec.latestPAttrV3Sent
+}
+
+func (ec *HiveRPCEngineClient) LatestNewPayloadV2Sent() *api.ExecutableDataV2 {
+	return ec.latestPayloadV2Sent
+}
+
+func (ec *HiveRPCEngineClient) LatestNewPayloadV3Sent() *api.ExecutableDataV3 {
+	return ec.latestPayloadV3Sent
+}
+
+func (ec *HiveRPCEngineClient) LatestGetPayloadV2Response() *api.GetPayloadV2Response {
+	return ec.latestGetPayloadV2Resp
+}
+
+func (ec *HiveRPCEngineClient) LatestGetPayloadV3Response() *api.GetPayloadV3Response {
+	return ec.latestGetPayloadV3Resp
+}
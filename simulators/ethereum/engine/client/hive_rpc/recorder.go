@@ -0,0 +1,145 @@
+package hive_rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EngineCallRecord is a single engine_* call/response pair, as written to
+// the recorder's JSONL log. It carries enough detail to attach to a failing
+// test artifact and to replay the call later via ReplayEngineClient.
+type EngineCallRecord struct {
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	LatencyMs int64           `json:"latencyMs"`
+	IssuedAt  int64           `json:"iat"`
+}
+
+// EngineCallRecorder wraps a HiveRPCEngineClient's engine-namespace calls,
+// writing each one to a JSONL log and keeping running per-method latency
+// and error-rate metrics, so long-running suites can flag pathologically
+// slow clients without instrumenting each test.
+type EngineCallRecorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	metrics map[string]*methodMetrics
+}
+
+type methodMetrics struct {
+	calls     int
+	errors    int
+	latencies []time.Duration
+}
+
+// NewEngineCallRecorder returns a recorder that appends one JSON object per
+// line to w. w may be nil, in which case only metrics are kept.
+func NewEngineCallRecorder(w io.Writer) *EngineCallRecorder {
+	return &EngineCallRecorder{
+		w:       w,
+		metrics: make(map[string]*methodMetrics),
+	}
+}
+
+func (r *EngineCallRecorder) record(method string, params []interface{}, result interface{}, callErr error, latency time.Duration, iat int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.metrics[method]
+	if m == nil {
+		m = &methodMetrics{}
+		r.metrics[method] = m
+	}
+	m.calls++
+	m.latencies = append(m.latencies, latency)
+	if callErr != nil {
+		m.errors++
+	}
+
+	if r.w == nil {
+		return
+	}
+	rec := EngineCallRecord{
+		Method:    method,
+		LatencyMs: latency.Milliseconds(),
+		IssuedAt:  iat,
+	}
+	if b, err := json.Marshal(params); err == nil {
+		rec.Params = b
+	}
+	if callErr != nil {
+		rec.Error = callErr.Error()
+	} else if b, err := json.Marshal(result); err == nil {
+		rec.Result = b
+	}
+	if b, err := json.Marshal(rec); err == nil {
+		b = append(b, '\n')
+		r.w.Write(b)
+	}
+}
+
+// MethodStats summarizes call volume, error rate and latency percentiles
+// for a single engine_* method.
+type MethodStats struct {
+	Calls  int
+	Errors int
+	P50    time.Duration
+	P99    time.Duration
+}
+
+// Metrics returns a per-method snapshot of everything recorded so far.
+func (r *EngineCallRecorder) Metrics() map[string]MethodStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]MethodStats, len(r.metrics))
+	for method, m := range r.metrics {
+		latencies := append([]time.Duration{}, m.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		out[method] = MethodStats{
+			Calls:  m.calls,
+			Errors: m.errors,
+			P50:    percentile(latencies, 0.50),
+			P99:    percentile(latencies, 0.99),
+		}
+	}
+	return out
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// SetCallRecorder attaches (or replaces) the recorder for this client's
+// engine_* calls.
+func (ec *HiveRPCEngineClient) SetCallRecorder(r *EngineCallRecorder) {
+	ec.recorder = r
+}
+
+// engineCallContext is the single call site every engine_* method on this
+// client routes through: it prepares the JWT auth header, times the call,
+// and reports it to the recorder if one is attached.
+func (ec *HiveRPCEngineClient) engineCallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	iat := time.Now()
+	if err := ec.PrepareAuthCallToken(ec.JWTSecretBytes, iat); err != nil {
+		return err
+	}
+	start := time.Now()
+	err := ec.c.CallContext(ctx, result, method, args...)
+	if ec.recorder != nil {
+		ec.recorder.record(method, args, result, err, time.Since(start), iat.Unix())
+	}
+	return err
+}
@@ -0,0 +1,218 @@
+package hive_rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	api "github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/hive/simulators/ethereum/engine/client"
+)
+
+// ReplayEngineClient sources engine_* responses from a JSONL log previously
+// written by an EngineCallRecorder, instead of a live client. It satisfies
+// client.EngineClient so it can be substituted wherever a test takes an
+// EngineClient, letting a simulator author reproduce a failing scenario's
+// exact engine-API transcript without booting the client that produced it.
+//
+// Only the engine_* transcript is recorded, so the embedded *ethclient.Client
+// is left nil: any eth-namespace call (BalanceAt, NonceAt, ...) made
+// directly against it will panic, the same way it would on a misused nil
+// pointer anywhere else in Go. HeaderByNumber and GetNextAccountNonce are
+// stubbed with a clear error instead, since callers are expected to reach
+// them through the normal EngineClient surface.
+//
+// Calls are matched to log entries strictly in recorded order: the first
+// unconsumed record for a given method is returned, and then discarded. A
+// replay that asks for more calls of a method than were recorded returns
+// io.EOF.
+type ReplayEngineClient struct {
+	*ethclient.Client
+
+	byMethod map[string][]EngineCallRecord
+
+	latestFcUStateSent *api.ForkchoiceStateV1
+	latestPAttrSent    *api.PayloadAttributesV1
+	latestFcUResponse  *api.ForkChoiceResponse
+
+	latestPayloadSent          *api.ExecutableDataV1
+	latestPayloadStatusReponse *api.PayloadStatusV1
+}
+
+var _ client.EngineClient = (*ReplayEngineClient)(nil)
+
+// NewReplayEngineClient reads every record from r and builds a client that
+// replays them back in recorded order.
+func NewReplayEngineClient(r io.Reader) (*ReplayEngineClient, error) {
+	rec := &ReplayEngineClient{byMethod: make(map[string][]EngineCallRecord)}
+	scanner := bufio.NewScanner(r)
+	// Recorded payloads (e.g. ExecutableDataV3) can comfortably exceed the
+	// default 64KiB line limit.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry EngineCallRecord
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("unable to decode recorded call: %v", err)
+		}
+		rec.byMethod[entry.Method] = append(rec.byMethod[entry.Method], entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (r *ReplayEngineClient) next(method string, result interface{}) error {
+	entries := r.byMethod[method]
+	if len(entries) == 0 {
+		return io.EOF
+	}
+	entry := entries[0]
+	r.byMethod[method] = entries[1:]
+	if entry.Error != "" {
+		return fmt.Errorf("%s", entry.Error)
+	}
+	if result == nil || len(entry.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(entry.Result, result)
+}
+
+func (r *ReplayEngineClient) ID() string {
+	return "replay"
+}
+
+func (r *ReplayEngineClient) EnodeURL() (string, error) {
+	return "", fmt.Errorf("replay client has no enode: engine-API transcripts don't record p2p info")
+}
+
+func (r *ReplayEngineClient) TerminalTotalDifficulty() *big.Int {
+	return nil
+}
+
+func (r *ReplayEngineClient) Close() error {
+	return nil
+}
+
+func (r *ReplayEngineClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return nil, fmt.Errorf("replay client does not record eth-namespace calls: HeaderByNumber is unavailable")
+}
+
+func (r *ReplayEngineClient) GetNextAccountNonce(ctx context.Context, account common.Address) (uint64, error) {
+	return 0, fmt.Errorf("replay client does not record eth-namespace calls: GetNextAccountNonce is unavailable")
+}
+
+func (r *ReplayEngineClient) PostRunVerifications() error {
+	return nil
+}
+
+func (r *ReplayEngineClient) LatestForkchoiceSent() (fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV1) {
+	return r.latestFcUStateSent, r.latestPAttrSent
+}
+
+func (r *ReplayEngineClient) LatestNewPayloadSent() *api.ExecutableDataV1 {
+	return r.latestPayloadSent
+}
+
+func (r *ReplayEngineClient) LatestForkchoiceResponse() *api.ForkChoiceResponse {
+	return r.latestFcUResponse
+}
+
+func (r *ReplayEngineClient) LatestNewPayloadResponse() *api.PayloadStatusV1 {
+	return r.latestPayloadStatusReponse
+}
+
+func (r *ReplayEngineClient) ForkchoiceUpdatedV1(ctx context.Context, fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV1) (api.ForkChoiceResponse, error) {
+	var result api.ForkChoiceResponse
+	r.latestFcUStateSent = fcState
+	r.latestPAttrSent = pAttributes
+	err := r.next("engine_forkchoiceUpdatedV1", &result)
+	r.latestFcUResponse = &result
+	return result, err
+}
+
+func (r *ReplayEngineClient) ForkchoiceUpdatedV2(ctx context.Context, fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV2) (api.ForkChoiceResponse, error) {
+	var result api.ForkChoiceResponse
+	r.latestFcUStateSent = fcState
+	err := r.next("engine_forkchoiceUpdatedV2", &result)
+	r.latestFcUResponse = &result
+	return result, err
+}
+
+func (r *ReplayEngineClient) ForkchoiceUpdatedV3(ctx context.Context, fcState *api.ForkchoiceStateV1, pAttributes *api.PayloadAttributesV3) (api.ForkChoiceResponse, error) {
+	var result api.ForkChoiceResponse
+	r.latestFcUStateSent = fcState
+	err := r.next("engine_forkchoiceUpdatedV3", &result)
+	r.latestFcUResponse = &result
+	return result, err
+}
+
+func (r *ReplayEngineClient) GetPayloadV1(ctx context.Context, payloadId *api.PayloadID) (api.ExecutableDataV1, error) {
+	var result api.ExecutableDataV1
+	err := r.next("engine_getPayloadV1", &result)
+	return result, err
+}
+
+func (r *ReplayEngineClient) GetPayloadV2(ctx context.Context, payloadId *api.PayloadID) (api.GetPayloadV2Response, error) {
+	var result api.GetPayloadV2Response
+	err := r.next("engine_getPayloadV2", &result)
+	return result, err
+}
+
+func (r *ReplayEngineClient) GetPayloadV3(ctx context.Context, payloadId *api.PayloadID) (api.GetPayloadV3Response, error) {
+	var result api.GetPayloadV3Response
+	err := r.next("engine_getPayloadV3", &result)
+	return result, err
+}
+
+func (r *ReplayEngineClient) NewPayloadV1(ctx context.Context, payload *api.ExecutableDataV1) (api.PayloadStatusV1, error) {
+	var result api.PayloadStatusV1
+	r.latestPayloadSent = payload
+	err := r.next("engine_newPayloadV1", &result)
+	r.latestPayloadStatusReponse = &result
+	return result, err
+}
+
+func (r *ReplayEngineClient) NewPayloadV2(ctx context.Context, payload *api.ExecutableDataV2) (api.PayloadStatusV1, error) {
+	var result api.PayloadStatusV1
+	err := r.next("engine_newPayloadV2", &result)
+	r.latestPayloadStatusReponse = &result
+	return result, err
+}
+
+func (r *ReplayEngineClient) NewPayloadV3(ctx context.Context, payload *api.ExecutableDataV3, versionedHashes []common.Hash, parentBeaconBlockRoot *common.Hash) (api.PayloadStatusV1, error) {
+	var result api.PayloadStatusV1
+	err := r.next("engine_newPayloadV3", &result)
+	r.latestPayloadStatusReponse = &result
+	return result, err
+}
+
+func (r *ReplayEngineClient) ExchangeTransitionConfigurationV1(ctx context.Context, tConf *api.TransitionConfigurationV1) (api.TransitionConfigurationV1, error) {
+	var result api.TransitionConfigurationV1
+	err := r.next("engine_exchangeTransitionConfigurationV1", &result)
+	return result, err
+}
+
+func (r *ReplayEngineClient) GetPayloadBodiesByHashV1(ctx context.Context, hashes []common.Hash) ([]*api.ExecutionPayloadBodyV1, error) {
+	var result []*api.ExecutionPayloadBodyV1
+	err := r.next("engine_getPayloadBodiesByHashV1", &result)
+	return result, err
+}
+
+func (r *ReplayEngineClient) GetPayloadBodiesByRangeV1(ctx context.Context, start, count uint64) ([]*api.ExecutionPayloadBodyV1, error) {
+	var result []*api.ExecutionPayloadBodyV1
+	err := r.next("engine_getPayloadBodiesByRangeV1", &result)
+	return result, err
+}
+
+func (r *ReplayEngineClient) ExchangeCapabilities(ctx context.Context, capabilities []string) ([]string, error) {
+	var result []string
+	err := r.next("engine_exchangeCapabilities", &result)
+	return result, err
+}
@@ -0,0 +1,255 @@
+package hive_rpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	api "github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// wsClient lazily dials the eth or engine namespace WebSocket endpoint on
+// first use, and again after reconnectWS has dropped the cached connection;
+// until then the cached connection is returned as is. Most tests
+// never subscribe to anything, so dialing eagerly in NewHiveRPCEngineClient
+// would add a connection per client for no benefit.
+func (ec *HiveRPCEngineClient) wsClient(ctx context.Context, engine bool) (*rpc.Client, error) {
+	ec.wsMu.Lock()
+	defer ec.wsMu.Unlock()
+	cur := &ec.cEthWS
+	port := ec.ethPortWS
+	if engine {
+		cur = &ec.cEngineWS
+		port = ec.enginePortWS
+	}
+	if *cur != nil {
+		return *cur, nil
+	}
+	c, err := rpc.DialContext(ctx, fmt.Sprintf("ws://%s:%d/", ec.h.IP, port))
+	if err != nil {
+		return nil, fmt.Errorf("unable to dial websocket endpoint: %v", err)
+	}
+	if engine {
+		token, err := GetNewToken(ec.JWTSecretBytes, time.Now())
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.SetHeader("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	*cur = c
+	return c, nil
+}
+
+// reconnectWS drops a cached connection so the next dial redials from
+// scratch, used whenever a subscribe call fails. An established
+// subscription that fails does not call it: watchOnce's redial reuses the
+// cached connection, and only drops it if that re-subscribe fails.
+func (ec *HiveRPCEngineClient) reconnectWS(engine bool) {
+	ec.wsMu.Lock()
+	defer ec.wsMu.Unlock()
+	if engine {
+		ec.cEngineWS = nil
+	} else {
+		ec.cEthWS = nil
+	}
+}
+
+// engineSub is the ethereum.Subscription handed back to callers of
+// Subscribe*. Unlike a plain rpc.ClientSubscription, its background watch
+// loop transparently redials and re-subscribes on a dropped connection
+// instead of just surfacing the error.
+type engineSub struct {
+	unsubCh chan struct{}
+	errCh   chan error
+	once    sync.Once
+}
+
+func newEngineSub() *engineSub {
+	return &engineSub{unsubCh: make(chan struct{}), errCh: make(chan error, 1)}
+}
+
+func (s *engineSub) Unsubscribe() {
+	s.once.Do(func() { close(s.unsubCh) })
+}
+
+func (s *engineSub) Err() <-chan error {
+	return s.errCh
+}
+
+// SubscribeNewHeads subscribes to the eth_subscribe("newHeads") feed.
+func (ec *HiveRPCEngineClient) SubscribeNewHeads(ctx context.Context) (chan *types.Header, ethereum.Subscription, error) {
+	inner, innerCh, err := ec.dialNewHeads(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make(chan *types.Header)
+	sub := newEngineSub()
+	go func() {
+		for {
+			inner, innerCh, err = watchOnce(ctx, sub, inner, innerCh, out, ec.dialNewHeads)
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out, sub, nil
+}
+
+func (ec *HiveRPCEngineClient) dialNewHeads(ctx context.Context) (ethereum.Subscription, chan *types.Header, error) {
+	c, err := ec.wsClient(ctx, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan *types.Header)
+	sub, err := ethclient.NewClient(c).SubscribeNewHead(ctx, ch)
+	if err != nil {
+		ec.reconnectWS(false)
+		return nil, nil, err
+	}
+	return sub, ch, nil
+}
+
+// SubscribeLogs subscribes to the eth_subscribe("logs") feed matching q.
+func (ec *HiveRPCEngineClient) SubscribeLogs(ctx context.Context, q ethereum.FilterQuery) (chan types.Log, ethereum.Subscription, error) {
+	dial := func(ctx context.Context) (ethereum.Subscription, chan types.Log, error) {
+		c, err := ec.wsClient(ctx, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch := make(chan types.Log)
+		sub, err := ethclient.NewClient(c).SubscribeFilterLogs(ctx, q, ch)
+		if err != nil {
+			ec.reconnectWS(false)
+			return nil, nil, err
+		}
+		return sub, ch, nil
+	}
+	inner, innerCh, err := dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make(chan types.Log)
+	sub := newEngineSub()
+	go func() {
+		for {
+			inner, innerCh, err = watchOnce(ctx, sub, inner, innerCh, out, dial)
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out, sub, nil
+}
+
+// SubscribeNewPendingTransactions subscribes to the eth_subscribe
+// ("newPendingTransactions") feed.
+func (ec *HiveRPCEngineClient) SubscribeNewPendingTransactions(ctx context.Context) (chan common.Hash, ethereum.Subscription, error) {
+	dial := func(ctx context.Context) (ethereum.Subscription, chan common.Hash, error) {
+		c, err := ec.wsClient(ctx, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch := make(chan common.Hash)
+		sub, err := ethclient.NewClient(c).SubscribeNewPendingTransactions(ctx, ch)
+		if err != nil {
+			ec.reconnectWS(false)
+			return nil, nil, err
+		}
+		return sub, ch, nil
+	}
+	inner, innerCh, err := dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make(chan common.Hash)
+	sub := newEngineSub()
+	go func() {
+		for {
+			inner, innerCh, err = watchOnce(ctx, sub, inner, innerCh, out, dial)
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out, sub, nil
+}
+
+// SubscribePayloadAttributes subscribes to the engine-namespace
+// "newPayloadAttributes" feed over the JWT-authenticated engine WebSocket,
+// reusing the same auth token scheme as the other engine_* calls.
+func (ec *HiveRPCEngineClient) SubscribePayloadAttributes(ctx context.Context) (chan *api.PayloadAttributesV2, ethereum.Subscription, error) {
+	dial := func(ctx context.Context) (ethereum.Subscription, chan *api.PayloadAttributesV2, error) {
+		c, err := ec.wsClient(ctx, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch := make(chan *api.PayloadAttributesV2)
+		sub, err := c.Subscribe(ctx, "engine", ch, "newPayloadAttributes")
+		if err != nil {
+			ec.reconnectWS(true)
+			return nil, nil, err
+		}
+		return sub, ch, nil
+	}
+	inner, innerCh, err := dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make(chan *api.PayloadAttributesV2)
+	sub := newEngineSub()
+	go func() {
+		for {
+			inner, innerCh, err = watchOnce(ctx, sub, inner, innerCh, out, dial)
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out, sub, nil
+}
+
+// watchOnce relays values from inner/innerCh to out until either the caller
+// unsubscribes, ctx is done, or the inner subscription reports an error. On
+// an inner error it redials via dial and returns the replacement
+// subscription/channel so the caller's loop can keep relaying; a redial
+// failure is reported on sub.Err() and ends the watch for good (the
+// returned error is non-nil in that case).
+func watchOnce[T any](ctx context.Context, sub *engineSub, inner ethereum.Subscription, innerCh chan T, out chan T, dial func(context.Context) (ethereum.Subscription, chan T, error)) (ethereum.Subscription, chan T, error) {
+	for {
+		select {
+		case <-sub.unsubCh:
+			inner.Unsubscribe()
+			return nil, nil, fmt.Errorf("unsubscribed")
+		case <-ctx.Done():
+			inner.Unsubscribe()
+			return nil, nil, ctx.Err()
+		case v := <-innerCh:
+			select {
+			case out <- v:
+			case <-sub.unsubCh:
+				inner.Unsubscribe()
+				return nil, nil, fmt.Errorf("unsubscribed")
+			case <-ctx.Done():
+				inner.Unsubscribe()
+				return nil, nil, ctx.Err()
+			}
+		case subErr := <-inner.Err():
+			newInner, newCh, dialErr := dial(ctx)
+			if dialErr != nil {
+				select {
+				case sub.errCh <- fmt.Errorf("subscription dropped (%v) and redial failed: %v", subErr, dialErr):
+				default:
+				}
+				return nil, nil, dialErr
+			}
+			return newInner, newCh, nil
+		}
+	}
+}
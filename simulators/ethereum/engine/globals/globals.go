@@ -0,0 +1,9 @@
+package globals
+
+// EthPortWS and EnginePortWS are the default WebSocket listener ports for
+// the eth and engine namespaces, respectively, alongside the existing
+// EthPortHTTP/EnginePortHTTP HTTP defaults.
+const (
+	EthPortWS    = 8546
+	EnginePortWS = 8552
+)